@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// QueryTypeAnnotations is the QueryType recognized by QueryData for overlaying
+// PI annotations/event-frame markers on top of a value series panel, similar
+// to how Prometheus exemplars ride alongside a matrix result.
+const QueryTypeAnnotations = "Annotations"
+
+// PIAnnotationItem is a single entry returned by PI Web API's
+// /elements/{webId}/annotations endpoint.
+type PIAnnotationItem struct {
+	Name         string    `json:"Name"`
+	Description  string    `json:"Description"`
+	Category     string    `json:"Category"`
+	CreationDate time.Time `json:"CreationDate"`
+}
+
+// PIAnnotationsResponse is the envelope PI Web API wraps annotation items in.
+type PIAnnotationsResponse struct {
+	Items []PIAnnotationItem `json:"Items"`
+}
+
+// queryAnnotations resolves a single Annotations-type DataQuery into a
+// Grafana annotation data.Frame. Unlike streamsets/calculation queries, it
+// talks to PI Web API's /elements/{webId}/annotations endpoint directly
+// rather than going through the streamsets batch pipeline, since the
+// annotation payload shape does not fit the PiBatchData union.
+func (d Datasource) queryAnnotations(ctx context.Context, query backend.DataQuery) backend.DataResponse {
+	var piQuery Query
+	tempJSON, err := json.Marshal(query)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("marshalling query: %s", err))
+	}
+	if err := json.Unmarshal(tempJSON, &piQuery); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("unmarshalling query: %s", err))
+	}
+
+	if !piQuery.Pi.Annotations.Enable {
+		return backend.DataResponse{Frames: data.Frames{}}
+	}
+
+	timeField := data.NewFieldFromFieldType(data.FieldTypeTime, 0)
+	timeField.Name = "time"
+	timeEndField := data.NewFieldFromFieldType(data.FieldTypeTime, 0)
+	timeEndField.Name = "timeEnd"
+	titleField := data.NewFieldFromFieldType(data.FieldTypeString, 0)
+	titleField.Name = "title"
+	textField := data.NewFieldFromFieldType(data.FieldTypeString, 0)
+	textField.Name = "text"
+	tagsField := data.NewFieldFromFieldType(data.FieldTypeJSON, 0)
+	tagsField.Name = "tags"
+
+	for _, target := range piQuery.Pi.getTargets() {
+		fullTargetPath := piQuery.Pi.getBasePath()
+		if piQuery.Pi.IsPiPoint {
+			fullTargetPath += "\\" + target
+		} else {
+			fullTargetPath += "|" + target
+		}
+		webID, err := d.getWebID(ctx, fullTargetPath, piQuery.Pi.IsPiPoint)
+		if err != nil {
+			log.DefaultLogger.Error("queryAnnotations: error getting WebID", "error", err)
+			continue
+		}
+
+		resource := "/elements/" + webID.WebID + "/annotations" + piQuery.getTimeRangeURIComponent()
+		if piQuery.Pi.Annotations.CategoryFilter != "" {
+			resource += "&categoryName=" + piQuery.Pi.Annotations.CategoryFilter
+		}
+		if piQuery.Pi.Annotations.NameFilter != "" {
+			resource += "&nameFilter=" + piQuery.Pi.Annotations.NameFilter
+		}
+
+		body, err := d.apiGet(ctx, resource)
+		if err != nil {
+			log.DefaultLogger.Error("queryAnnotations: error fetching annotations", "error", err)
+			continue
+		}
+
+		var annotations PIAnnotationsResponse
+		if err := json.Unmarshal(body, &annotations); err != nil {
+			log.DefaultLogger.Error("queryAnnotations: error unmarshalling annotations", "error", err)
+			continue
+		}
+
+		for _, item := range annotations.Items {
+			timeField.Append(item.CreationDate)
+			timeEndField.Append(item.CreationDate)
+			titleField.Append(item.Name)
+			textField.Append(item.Description)
+			tagsField.Append(json.RawMessage(fmt.Sprintf("[%q]", item.Category)))
+		}
+	}
+
+	frame := data.NewFrame("annotations", timeField, timeEndField, titleField, textField, tagsField)
+	frame.Meta = &data.FrameMeta{DataTopic: data.DataTopicAnnotations}
+
+	return backend.DataResponse{Frames: data.Frames{frame}}
+}
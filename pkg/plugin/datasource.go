@@ -2,12 +2,14 @@ package plugin
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/complacentsee/osisoftpi-grafana/pkg/rules"
 	"github.com/go-co-op/gocron"
 	"github.com/gorilla/websocket"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -42,22 +44,44 @@ func NewDatasource(settings backend.DataSourceInstanceSettings) (instancemgmt.In
 	var webIDCache = map[string]WebIDCacheEntry{}
 	var channelConstruct = map[string]StreamChannelConstruct{}
 
+	rulesManager := rules.NewManager()
+
 	scheduler := gocron.NewScheduler(time.UTC)
 	scheduler.Every(5).Minute().Do(cleanWebIDCache, webIDCache)
-	scheduler.StartAsync()
 
-	return &Datasource{
+	idleTimeout := defaultIdleTimeout
+	var jsonSettings dataSourceJSONSettings
+	if err := json.Unmarshal(settings.JSONData, &jsonSettings); err != nil {
+		log.DefaultLogger.Warn("NewDatasource: failed to parse jsonData, using default idle timeout", "error", err)
+	} else if jsonSettings.IdleTimeoutSeconds > 0 {
+		idleTimeout = time.Duration(jsonSettings.IdleTimeoutSeconds) * time.Second
+	}
+	if jsonSettings.RulesYAML != "" {
+		if err := rulesManager.LoadYAML([]byte(jsonSettings.RulesYAML)); err != nil {
+			log.DefaultLogger.Error("NewDatasource: failed to load rules YAML", "error", err)
+		}
+	}
+
+	ds := &Datasource{
 		settings:                  settings,
 		httpClient:                cl,
 		webIDCache:                webIDCache,
 		channelConstruct:          channelConstruct,
+		channelConstructMutex:     &sync.Mutex{},
 		scheduler:                 scheduler,
 		websocketConnectionsMutex: &sync.Mutex{},
 		sendersByWebIDMutex:       &sync.Mutex{},
 		websocketConnections:      make(map[string]*websocket.Conn),
-		sendersByWebID:            make(map[string]map[*backend.StreamSender]bool),
+		sendersByWebID:            make(map[string]map[*backend.StreamSender]*streamSenderDeadline),
 		streamChannels:            make(map[string]chan []byte),
-	}, nil
+		idleTimeout:               idleTimeout,
+		rulesManager:              rulesManager,
+	}
+
+	scheduler.Every(rulesEvalInterval).Do(ds.evaluateRules)
+	scheduler.StartAsync()
+
+	return ds, nil
 }
 
 // Dispose here tells plugin SDK that plugin wants to clean up resources when a new instance
@@ -78,9 +102,21 @@ func (d *Datasource) Dispose() {
 func (d *Datasource) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
 	processedQueries := make(map[string][]PiProcessedQuery)
 	datasourceUID := req.PluginContext.DataSourceInstanceSettings.UID
+	response := backend.NewQueryDataResponse()
 
-	// Process queries and turn them into a suitable format for the PI Web API
+	// Process queries and turn them into a suitable format for the PI Web API.
+	// Annotations queries are resolved separately since they talk to a
+	// different PI Web API endpoint and return annotation frames rather than
+	// streamsets data.
 	for _, q := range req.Queries {
+		if q.QueryType == QueryTypeAnnotations {
+			response.Responses[q.RefID] = d.queryAnnotations(ctx, q)
+			continue
+		}
+		if handled, dataResponse := d.queryRecordingRule(q); handled {
+			response.Responses[q.RefID] = dataResponse
+			continue
+		}
 		processedQueries[q.RefID] = d.processQuery(ctx, q, datasourceUID)
 	}
 
@@ -88,12 +124,22 @@ func (d *Datasource) QueryData(ctx context.Context, req *backend.QueryDataReques
 	processedQueries_temp := d.batchRequest(ctx, processedQueries)
 
 	// Convert the PI Web API response into Grafana frames
-	response := d.processBatchtoFrames(processedQueries_temp)
+	frameResponse := d.processBatchtoFrames(processedQueries_temp)
+	for RefID, dataResponse := range frameResponse.Responses {
+		response.Responses[RefID] = dataResponse
+	}
 
 	return response, nil
 }
 
 func (d *Datasource) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	if strings.HasPrefix(req.Path, promAPIBasePath) {
+		return d.callPromAPI(ctx, req, sender)
+	}
+	if strings.HasPrefix(req.Path, "/rules") || strings.HasPrefix(req.Path, "/alerts") {
+		return d.callRulesAPI(req, sender)
+	}
+
 	var isAllowed = true
 	var allowedBasePaths = []string{
 		"/assetdatabases",
@@ -3,7 +3,9 @@ package plugin
 import (
 	"net/http"
 	"sync"
+	"time"
 
+	"github.com/complacentsee/osisoftpi-grafana/pkg/rules"
 	"github.com/go-co-op/gocron"
 	"github.com/gorilla/websocket"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -17,10 +19,34 @@ type Datasource struct {
 	httpClient                *http.Client
 	webIDCache                map[string]WebIDCacheEntry
 	channelConstruct          map[string]StreamChannelConstruct
+	channelConstructMutex     *sync.Mutex
 	scheduler                 *gocron.Scheduler
 	websocketConnectionsMutex *sync.Mutex
 	sendersByWebIDMutex       *sync.Mutex
 	websocketConnections      map[string]*websocket.Conn
-	sendersByWebID            map[string]map[*backend.StreamSender]bool
+	sendersByWebID            map[string]map[*backend.StreamSender]*streamSenderDeadline
 	streamChannels            map[string]chan []byte
+	// idleTimeout bounds how long a sender may go without its deadline being
+	// refreshed before it is considered wedged and dropped. Configured via
+	// the "idleTimeout" key in DataSourceInstanceSettings JSON data.
+	idleTimeout time.Duration
+	// rulesManager evaluates PI calculation expressions on a schedule and
+	// tracks alerting/recording rule state for the /rules and /alerts
+	// CallResource routes. Always non-nil; it starts with zero rule groups
+	// loaded until dataSourceJSONSettings.RulesYAML is parsed in
+	// NewDatasource.
+	rulesManager *rules.Manager
+}
+
+// dataSourceJSONSettings mirrors the subset of DataSourceInstanceSettings'
+// JSONData that this plugin reads at NewDatasource time.
+type dataSourceJSONSettings struct {
+	// IdleTimeoutSeconds is how long a websocket stream sender may sit idle
+	// before it is dropped. Zero/omitted falls back to defaultIdleTimeout.
+	IdleTimeoutSeconds int `json:"idleTimeout"`
+	// RulesYAML is an inline Prometheus/Thanos-style rule group YAML
+	// document, loaded into the rules manager at startup. Empty/omitted
+	// leaves the rules manager with no groups, so /rules and /alerts report
+	// nothing and the evaluation scheduler job is a no-op.
+	RulesYAML string `json:"rulesYaml"`
 }
@@ -0,0 +1,303 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// promAPIBasePath is the CallResource route prefix that exposes a
+// Prometheus-compatible HTTP query API backed by PI Web API lookups, so
+// external tools that speak the Prometheus HTTP API (Thanos, alertmanagers,
+// client_golang based scrapers) can be pointed directly at this datasource.
+const promAPIBasePath = "/promapi/v1/"
+
+// promResponse mirrors the envelope Prometheus' HTTP API wraps every
+// /api/v1/* response in.
+type promResponse struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+type promQueryData struct {
+	ResultType string       `json:"resultType"`
+	Result     []promMatrix `json:"result"`
+}
+
+type promMatrix struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]interface{}  `json:"values,omitempty"`
+	Value  *[2]interface{}   `json:"value,omitempty"`
+}
+
+// callPromAPI dispatches requests under promAPIBasePath to the matching
+// Prometheus API handler and writes back a Prometheus-shaped JSON envelope.
+func (d *Datasource) callPromAPI(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	route := strings.TrimPrefix(req.Path, promAPIBasePath)
+	parsedURL, err := url.Parse(req.URL)
+	if err != nil {
+		return sendPromError(sender, http.StatusBadRequest, "bad_data", err)
+	}
+	query := parsedURL.Query()
+
+	switch {
+	case route == "query":
+		return d.promInstantQuery(ctx, query, sender)
+	case route == "query_range":
+		return d.promRangeQuery(ctx, query, sender)
+	case route == "series":
+		return d.promSeries(ctx, query, sender)
+	case route == "labels":
+		return d.promLabels(ctx, sender)
+	case strings.HasPrefix(route, "label/") && strings.HasSuffix(route, "/values"):
+		name := strings.TrimSuffix(strings.TrimPrefix(route, "label/"), "/values")
+		return d.promLabelValues(ctx, name, sender)
+	case route == "metadata":
+		return d.promMetadata(ctx, sender)
+	default:
+		return sendPromError(sender, http.StatusNotFound, "not_found", fmt.Errorf("unknown promapi route %q", route))
+	}
+}
+
+// promInstantQuery services /promapi/v1/query: a single point in time,
+// resolved to the last interpolated value of the one-minute window ending at
+// "time".
+func (d *Datasource) promInstantQuery(ctx context.Context, query url.Values, sender backend.CallResourceResponseSender) error {
+	at, err := parsePromTime(query.Get("time"), time.Now())
+	if err != nil {
+		return sendPromError(sender, http.StatusBadRequest, "bad_data", err)
+	}
+	matrices, err := d.runPromQuery(ctx, query.Get("query"), query.Get("isPiPoint") == "true", at.Add(-time.Minute), at, time.Minute)
+	if err != nil {
+		return sendPromError(sender, http.StatusUnprocessableEntity, "execution", err)
+	}
+	for i := range matrices {
+		if len(matrices[i].Values) > 0 {
+			last := matrices[i].Values[len(matrices[i].Values)-1]
+			matrices[i].Value = &last
+		}
+		matrices[i].Values = nil
+	}
+	return sendPromData(sender, promQueryData{ResultType: "vector", Result: matrices})
+}
+
+// promRangeQuery services /promapi/v1/query_range, translating start/end/step
+// into a PIWebAPIQuery and reusing the normal processQuery/batchRequest/
+// processBatchtoFrames pipeline before re-marshaling into a Prometheus matrix.
+func (d *Datasource) promRangeQuery(ctx context.Context, query url.Values, sender backend.CallResourceResponseSender) error {
+	start, err := parsePromTime(query.Get("start"), time.Now().Add(-time.Hour))
+	if err != nil {
+		return sendPromError(sender, http.StatusBadRequest, "bad_data", err)
+	}
+	end, err := parsePromTime(query.Get("end"), time.Now())
+	if err != nil {
+		return sendPromError(sender, http.StatusBadRequest, "bad_data", err)
+	}
+	step, err := parsePromDuration(query.Get("step"))
+	if err != nil {
+		return sendPromError(sender, http.StatusBadRequest, "bad_data", err)
+	}
+
+	matrices, err := d.runPromQuery(ctx, query.Get("query"), query.Get("isPiPoint") == "true", start, end, step)
+	if err != nil {
+		return sendPromError(sender, http.StatusUnprocessableEntity, "execution", err)
+	}
+	return sendPromData(sender, promQueryData{ResultType: "matrix", Result: matrices})
+}
+
+// runPromQuery builds a single RefID worth of processed queries for the PI
+// target path given in "query" (e.g. "AFDatabase\\Element|Attribute;Tag1")
+// and converts the resulting frames into Prometheus matrix series. isPiPoint
+// mirrors PIWebAPIQuery.IsPiPoint: false (the default, matching promSeries
+// and promLabelValues, which both resolve targets as AF element attributes)
+// joins each target onto the base path with "|"; true joins with "\" to
+// address raw PI points instead. Callers select it with the "isPiPoint"
+// query-string parameter.
+func (d *Datasource) runPromQuery(ctx context.Context, target string, isPiPoint bool, start, end time.Time, step time.Duration) ([]promMatrix, error) {
+	if target == "" {
+		return nil, fmt.Errorf("missing required query parameter %q", "query")
+	}
+	if !strings.Contains(target, ";") {
+		return nil, fmt.Errorf("query parameter must be of the form \"<base path>;<target1>;...\", got %q", target)
+	}
+
+	piQuery := Query{}
+	piQuery.TimeRange.From = start
+	piQuery.TimeRange.To = end
+	piQuery.Interval = step.Milliseconds()
+	piQuery.Pi = PIWebAPIQuery{
+		Target:    target,
+		IsPiPoint: isPiPoint,
+		Interpolate: struct {
+			Enable bool `json:"enable"`
+		}{Enable: true},
+		IntervalMs: int(step.Milliseconds()),
+	}
+
+	processed := map[string][]PiProcessedQuery{"promapi": d.processPIQuery(ctx, piQuery, "promapi")}
+	processed = d.batchRequest(ctx, processed)
+	response := d.processBatchtoFrames(processed)
+
+	dataResponse, ok := response.Responses["promapi"]
+	if !ok {
+		return nil, fmt.Errorf("no data returned for query %q", target)
+	}
+	if dataResponse.Error != nil {
+		return nil, dataResponse.Error
+	}
+
+	var matrices []promMatrix
+	for _, frame := range dataResponse.Frames {
+		matrices = append(matrices, frameToPromMatrix(frame))
+	}
+	return matrices, nil
+}
+
+// frameToPromMatrix converts a time/value data.Frame produced by
+// convertItemsToDataFrame into a Prometheus matrix series, labelling it by
+// the frame's own name (the PI target label).
+func frameToPromMatrix(frame *data.Frame) promMatrix {
+	metric := map[string]string{"__name__": frame.Name}
+	matrix := promMatrix{Metric: metric}
+	if len(frame.Fields) < 2 {
+		return matrix
+	}
+	timeField, valueField := frame.Fields[0], frame.Fields[1]
+	for i := 0; i < timeField.Len(); i++ {
+		ts, ok := timeField.At(i).(time.Time)
+		if !ok {
+			continue
+		}
+		matrix.Values = append(matrix.Values, [2]interface{}{
+			float64(ts.Unix()),
+			fmt.Sprintf("%v", valueField.At(i)),
+		})
+	}
+	return matrix
+}
+
+// promSeries services /promapi/v1/series by treating each "match[]" target as
+// an AF element path (isPiPoint false, the same default runPromQuery uses)
+// and resolving it through the existing /elements lookup, returning one
+// label set per matched element/attribute.
+func (d *Datasource) promSeries(ctx context.Context, query url.Values, sender backend.CallResourceResponseSender) error {
+	var series []map[string]string
+	for _, match := range query["match[]"] {
+		WebID, err := d.getWebID(ctx, match, false)
+		if err != nil {
+			log.DefaultLogger.Error("promapi series: error resolving match", "match", match, "error", err)
+			continue
+		}
+		series = append(series, map[string]string{
+			"__name__": match,
+			"webid":    WebID.WebID,
+		})
+	}
+	return sendPromData(sender, series)
+}
+
+// promLabels services /promapi/v1/labels. PI exposes no generic label
+// dimension, so this reports the fixed set of labels promSeries emits
+// (__name__ and webid). Series returned by /query and /query_range carry
+// only __name__.
+func (d *Datasource) promLabels(ctx context.Context, sender backend.CallResourceResponseSender) error {
+	return sendPromData(sender, []string{"__name__", "webid"})
+}
+
+// promLabelValues services /promapi/v1/label/<name>/values by listing the
+// item names returned by /assetdatabases (for __name__) or the WebIDs
+// returned by /points (for webid), reshaping the PI Web API listing into the
+// flat string array Prometheus clients expect. Only that top-level listing
+// is read: elements and their attributes are not walked.
+func (d *Datasource) promLabelValues(ctx context.Context, name string, sender backend.CallResourceResponseSender) error {
+	var resource string
+	switch name {
+	case "__name__":
+		resource = "/assetdatabases"
+	case "webid":
+		resource = "/points"
+	default:
+		return sendPromData(sender, []string{})
+	}
+
+	r, err := d.apiGet(ctx, resource)
+	if err != nil {
+		return sendPromError(sender, http.StatusInternalServerError, "internal", err)
+	}
+
+	var listing struct {
+		Items []struct {
+			Name  string `json:"Name"`
+			WebId string `json:"WebId"`
+		} `json:"Items"`
+	}
+	if err := json.Unmarshal(r, &listing); err != nil {
+		return sendPromError(sender, http.StatusInternalServerError, "internal", err)
+	}
+
+	values := make([]string, 0, len(listing.Items))
+	for _, item := range listing.Items {
+		if name == "webid" {
+			values = append(values, item.WebId)
+		} else {
+			values = append(values, item.Name)
+		}
+	}
+	return sendPromData(sender, values)
+}
+
+// promMetadata services /promapi/v1/metadata. There is no PI concept of
+// metric type/help text, so it always returns an empty metadata map; no
+// series is given a type.
+func (d *Datasource) promMetadata(ctx context.Context, sender backend.CallResourceResponseSender) error {
+	return sendPromData(sender, map[string]interface{}{})
+}
+
+func parsePromTime(raw string, fallback time.Time) (time.Time, error) {
+	if raw == "" {
+		return fallback, nil
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		sec := int64(f)
+		nsec := int64((f - float64(sec)) * float64(time.Second))
+		return time.Unix(sec, nsec).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+func parsePromDuration(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 15 * time.Second, nil
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	return time.ParseDuration(raw)
+}
+
+func sendPromData(sender backend.CallResourceResponseSender, data interface{}) error {
+	body, err := json.Marshal(promResponse{Status: "success", Data: data})
+	if err != nil {
+		return err
+	}
+	return sender.Send(&backend.CallResourceResponse{Status: http.StatusOK, Body: body})
+}
+
+func sendPromError(sender backend.CallResourceResponseSender, status int, errType string, err error) error {
+	body, marshalErr := json.Marshal(promResponse{Status: "error", ErrorType: errType, Error: err.Error()})
+	if marshalErr != nil {
+		return marshalErr
+	}
+	return sender.Send(&backend.CallResourceResponse{Status: status, Body: body})
+}
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPromQueryRejectsTargetWithoutSemicolon(t *testing.T) {
+	d := &Datasource{}
+	_, err := d.runPromQuery(nil, "Tag1", false, time.Now().Add(-time.Minute), time.Now(), time.Minute)
+	if err == nil {
+		t.Fatal("expected an error for a query target with no \";\" separator, got nil")
+	}
+	if !strings.Contains(err.Error(), "query parameter must be of the form") {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+}
+
+func TestPromMatrixValueOmitsWhenNil(t *testing.T) {
+	m := promMatrix{Metric: map[string]string{"__name__": "x"}}
+	body, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if strings.Contains(string(body), "\"value\"") {
+		t.Fatalf("expected \"value\" to be omitted when nil, got %s", body)
+	}
+}
+
+func TestPromMatrixValuePresentWhenSet(t *testing.T) {
+	v := [2]interface{}{float64(1000), "42"}
+	m := promMatrix{Metric: map[string]string{"__name__": "x"}, Value: &v}
+	body, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if !strings.Contains(string(body), "\"value\"") {
+		t.Fatalf("expected \"value\" to be present when set, got %s", body)
+	}
+}
+
+func TestParsePromTimeDefaultsWhenEmpty(t *testing.T) {
+	fallback := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	got, err := parsePromTime("", fallback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Equal(fallback) {
+		t.Fatalf("expected fallback time %v, got %v", fallback, got)
+	}
+}
+
+func TestParsePromDurationDefaultsWhenEmpty(t *testing.T) {
+	got, err := parsePromDuration("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 15*time.Second {
+		t.Fatalf("expected default 15s step, got %v", got)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 )
 
 type Query struct {
@@ -47,6 +48,11 @@ func (q *Query) isStreamable() bool {
 }
 
 type PIWebAPIQuery struct {
+	Annotations struct {
+		Enable         bool   `json:"Enable"`
+		CategoryFilter string `json:"CategoryFilter"`
+		NameFilter     string `json:"NameFilter"`
+	} `json:"Annotations"`
 	Attributes []struct {
 		Label string `json:"label"`
 		Value struct {
@@ -90,6 +96,7 @@ type PIWebAPIQuery struct {
 			WebID      string `json:"webId"`
 		} `json:"value"`
 	} `json:"segments"`
+	Stats   bool         `json:"stats"`
 	Summary QuerySummary `json:"summary"`
 	Target  string       `json:"target"`
 }
@@ -122,6 +129,58 @@ type PiProcessedQuery struct {
 	ResponseUnits       string
 	BatchRequest        BatchSubRequest `json:"BatchRequest"`
 	Response            PiBatchData     `json:"ResponseData"`
+	Stats               PiQueryStats    `json:"-"`
+}
+
+// PiQueryStats records the Prometheus-style execution stats (`stats=all`)
+// gathered while a PiProcessedQuery's batch sub-request was resolved. It is
+// surfaced to the caller as data.QueryStat entries on frame.Meta.Stats.
+type PiQueryStats struct {
+	PIWebAPIRequestDurationMs int64
+	WebIDCacheHit             bool
+	BatchSubRequestCount      int
+	ItemsReturned             int
+	ResponseBytes             int
+	// PerStepBreakdown is set when the originating PIWebAPIQuery asked for
+	// stats on a summary query: one QueryStat row is emitted per summary
+	// interval instead of a single aggregate ItemsReturned row.
+	PerStepBreakdown bool
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// toQueryStats renders the collected stats as the data.QueryStat rows
+// attached to frame.Meta.Stats. When PerStepBreakdown is set, items supplies
+// one PiBatchContentItem per summary interval so a stat row can be emitted
+// for each step rather than a single aggregate count.
+func (s PiQueryStats) toQueryStats(items []PiBatchContentItem) []data.QueryStat {
+	stats := []data.QueryStat{
+		{FieldConfig: data.FieldConfig{DisplayNameFromDS: "PI Web API request duration (ms)"}, Value: float64(s.PIWebAPIRequestDurationMs)},
+		{FieldConfig: data.FieldConfig{DisplayNameFromDS: "WebID cache hit"}, Value: boolToFloat(s.WebIDCacheHit)},
+		{FieldConfig: data.FieldConfig{DisplayNameFromDS: "Batch sub-request count"}, Value: float64(s.BatchSubRequestCount)},
+		{FieldConfig: data.FieldConfig{DisplayNameFromDS: "Response bytes"}, Value: float64(s.ResponseBytes)},
+	}
+
+	if !s.PerStepBreakdown {
+		stats = append(stats, data.QueryStat{
+			FieldConfig: data.FieldConfig{DisplayNameFromDS: "Items returned"},
+			Value:       float64(s.ItemsReturned),
+		})
+		return stats
+	}
+
+	for i, item := range items {
+		stats = append(stats, data.QueryStat{
+			FieldConfig: data.FieldConfig{DisplayNameFromDS: fmt.Sprintf("summary step %d (%s)", i, item.Timestamp)},
+			Value:       1,
+		})
+	}
+	return stats
 }
 
 type BatchSubRequest struct {
@@ -142,6 +201,7 @@ func (d Datasource) processBatchtoFrames(processedQuery map[string][]PiProcessed
 			frame, err := convertItemsToDataFrame(q.Label, *q.Response.getItems(), Type, DigitalState, false)
 			frame.RefID = RefID
 			frame.Meta.ExecutedQueryString = q.BatchRequest.Resource
+			frame.Meta.Stats = q.Stats.toQueryStats(*q.Response.getItems())
 
 			if err != nil {
 				backend.Logger.Error("Error processing query", "RefID", RefID, "QueryIndex", i)
@@ -162,7 +222,9 @@ func (d Datasource) processBatchtoFrames(processedQuery map[string][]PiProcessed
 					WebID:               q.WebID,
 					IntervalNanoSeconds: q.IntervalNanoSeconds,
 				}
+				d.channelConstructMutex.Lock()
 				d.channelConstruct[channeluuid.String()] = channel
+				d.channelConstructMutex.Unlock()
 				frame.Meta.Channel = channelURI
 			}
 
@@ -181,7 +243,9 @@ func (d Datasource) batchRequest(ctx context.Context, processedQuery map[string]
 		for i, p := range processed {
 			batchRequest[fmt.Sprint(i)] = p.BatchRequest
 		}
+		requestStart := time.Now()
 		r, err := d.apiBatchRequest(ctx, batchRequest)
+		durationMs := time.Since(requestStart).Milliseconds()
 		if err != nil {
 			log.DefaultLogger.Error("Error in batch request", "error", err)
 			continue
@@ -196,6 +260,10 @@ func (d Datasource) batchRequest(ctx context.Context, processedQuery map[string]
 
 		for i := range processed {
 			processedQuery[RefID][i].Response = tempresponse[i].Content
+			processedQuery[RefID][i].Stats.PIWebAPIRequestDurationMs = durationMs
+			processedQuery[RefID][i].Stats.BatchSubRequestCount = len(processed)
+			processedQuery[RefID][i].Stats.ResponseBytes = len(r)
+			processedQuery[RefID][i].Stats.ItemsReturned = len(*tempresponse[i].Content.getItems())
 		}
 	}
 	return processedQuery
@@ -317,6 +385,15 @@ func (d Datasource) processQuery(ctx context.Context, query backend.DataQuery, d
 		return response
 	}
 
+	return d.processPIQuery(ctx, PiQuery, datasourceUID)
+}
+
+// processPIQuery builds the batch sub-requests for a single parsed Query. It is
+// the shared core behind processQuery (Grafana's QueryData path) and the
+// /promapi/v1 CallResource routes, which construct a Query from raw HTTP
+// query-string parameters instead of a backend.DataQuery.
+func (d Datasource) processPIQuery(ctx context.Context, PiQuery Query, datasourceUID string) []PiProcessedQuery {
+	var response []PiProcessedQuery
 	for _, target := range PiQuery.Pi.getTargets() {
 		fullTargetPath := PiQuery.Pi.getBasePath()
 		if PiQuery.Pi.IsPiPoint {
@@ -324,6 +401,7 @@ func (d Datasource) processQuery(ctx context.Context, query backend.DataQuery, d
 		} else {
 			fullTargetPath += "|" + target
 		}
+		_, cacheHit := d.webIDCache[fullTargetPath]
 		WebID, err := d.getWebID(ctx, fullTargetPath, PiQuery.Pi.IsPiPoint)
 		if err != nil {
 			log.DefaultLogger.Error("Error getting WebID", "error", err)
@@ -343,6 +421,10 @@ func (d Datasource) processQuery(ctx context.Context, query backend.DataQuery, d
 			Streamable:          PiQuery.isStreamable(),
 			FullTargetPath:      fullTargetPath,
 			BatchRequest:        batchSubRequest,
+			Stats: PiQueryStats{
+				WebIDCacheHit:    cacheHit,
+				PerStepBreakdown: PiQuery.Pi.Stats && PiQuery.Pi.isSummary(),
+			},
 		}
 		response = append(response, piQuery)
 	}
@@ -0,0 +1,153 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/complacentsee/osisoftpi-grafana/pkg/rules"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// rulesEvalInterval is how often the rules scheduler job re-evaluates every
+// loaded rule's PI calculation expression.
+const rulesEvalInterval = time.Minute
+
+// evaluateRules is the gocron job that drives d.rulesManager: it resolves
+// every loaded rule's expression through PI Web API and updates alerting/
+// recording state accordingly.
+func (d *Datasource) evaluateRules() {
+	ctx := context.Background()
+	d.rulesManager.Evaluate(time.Now(), func(rule rules.Rule) (float64, error) {
+		return d.evaluateExpression(ctx, rule)
+	})
+}
+
+// evaluateExpression runs a single rule's PI calculation expression through
+// the same processPIQuery/batchRequest pipeline QueryData uses for
+// expression targets, so it gets a webid-anchored calculation request
+// exactly like any other calculation query, and returns its most recent
+// scalar result. rule.Context must be an AF element path in the same
+// "<base path>;<target>" shape as PIWebAPIQuery.Target, used to resolve the
+// expression's relative references. IsPiPoint is never set, so each target
+// is joined to the base path with "|"; raw PI point paths are not resolved.
+func (d *Datasource) evaluateExpression(ctx context.Context, rule rules.Rule) (float64, error) {
+	if rule.Context == "" {
+		return 0, fmt.Errorf("rule %q has no context element/point path to anchor its expression", rule.ID())
+	}
+	if !strings.Contains(rule.Context, ";") {
+		return 0, fmt.Errorf("rule %q has a malformed context %q, expected \"<base path>;<target1>;...\"", rule.ID(), rule.Context)
+	}
+
+	now := time.Now()
+	piQuery := Query{}
+	piQuery.TimeRange.From = now.Add(-rulesEvalInterval)
+	piQuery.TimeRange.To = now
+	piQuery.Interval = rulesEvalInterval.Milliseconds()
+	piQuery.Pi = PIWebAPIQuery{
+		Expression: rule.Expression,
+		Target:     rule.Context,
+		Interpolate: struct {
+			Enable bool `json:"enable"`
+		}{Enable: true},
+		IntervalMs: int(rulesEvalInterval.Milliseconds()),
+	}
+
+	processed := map[string][]PiProcessedQuery{"rule": d.processPIQuery(ctx, piQuery, "rule")}
+	processed = d.batchRequest(ctx, processed)
+
+	results := processed["rule"]
+	if len(results) == 0 {
+		return 0, fmt.Errorf("no result resolving rule %q", rule.ID())
+	}
+
+	items := *results[0].Response.getItems()
+	if len(items) == 0 {
+		return 0, fmt.Errorf("calculation returned no items for rule %q", rule.ID())
+	}
+
+	return parsePIScalar(items[len(items)-1].Value)
+}
+
+func parsePIScalar(v interface{}) (float64, error) {
+	switch value := v.(type) {
+	case float64:
+		return value, nil
+	case string:
+		return strconv.ParseFloat(value, 64)
+	default:
+		return 0, fmt.Errorf("unexpected PI calculation value type %T", v)
+	}
+}
+
+// callRulesAPI serves the Prometheus-compatible /rules and /alerts routes so
+// Grafana's Alerting UI and external Alertmanagers can scrape rule state
+// directly from this datasource.
+func (d *Datasource) callRulesAPI(req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	switch {
+	case strings.HasPrefix(req.Path, "/rules"):
+		return sendPromData(sender, d.rulesManager.RulesJSON())
+	case strings.HasPrefix(req.Path, "/alerts"):
+		return sendPromData(sender, d.rulesManager.AlertsJSON())
+	default:
+		return sendPromError(sender, http.StatusNotFound, "not_found", fmt.Errorf("unknown rules route %q", req.Path))
+	}
+}
+
+// recordingRuleBasePath is the reserved PIWebAPIQuery base path that opts a
+// target into the recording-rule cache lookup in queryRecordingRule, e.g.
+// "recordingrule;cached_value". It can never collide with a real PI element
+// or point path, which always resolves through getWebID.
+const recordingRuleBasePath = "recordingrule"
+
+// queryRecordingRule answers a QueryData target directly from the rules
+// manager's cached recording-rule values when the query is explicitly
+// addressed to recordingRuleBasePath, avoiding a round-trip to PI Web API.
+// The bool return reports whether the query was handled.
+func (d *Datasource) queryRecordingRule(query backend.DataQuery) (bool, backend.DataResponse) {
+	var piQuery Query
+	tempJSON, err := json.Marshal(query)
+	if err != nil {
+		return false, backend.DataResponse{}
+	}
+	if err := json.Unmarshal(tempJSON, &piQuery); err != nil {
+		return false, backend.DataResponse{}
+	}
+	if piQuery.Pi.isExpression() || !strings.Contains(piQuery.Pi.Target, ";") {
+		return false, backend.DataResponse{}
+	}
+	if piQuery.Pi.getBasePath() != recordingRuleBasePath {
+		return false, backend.DataResponse{}
+	}
+
+	targets := piQuery.Pi.getTargets()
+	if len(targets) != 1 {
+		return false, backend.DataResponse{}
+	}
+
+	value, ok := d.rulesManager.RecordingValue(targets[0])
+	if !ok {
+		return false, backend.DataResponse{}
+	}
+
+	timeField := data.NewFieldFromFieldType(data.FieldTypeTime, 2)
+	timeField.Set(0, piQuery.TimeRange.From)
+	timeField.Set(1, piQuery.TimeRange.To)
+	valueField := data.NewFieldFromFieldType(data.FieldTypeFloat64, 2)
+	valueField.Name = targets[0]
+	valueField.Set(0, value)
+	valueField.Set(1, value)
+
+	frame := data.NewFrame(targets[0], timeField, valueField)
+	frame.RefID = query.RefID
+	frame.Meta = &data.FrameMeta{
+		Notices: []data.Notice{{Severity: data.NoticeSeverityInfo, Text: "served from cached recording rule value"}},
+	}
+
+	return true, backend.DataResponse{Frames: data.Frames{frame}}
+}
@@ -0,0 +1,48 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/complacentsee/osisoftpi-grafana/pkg/rules"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestEvaluateExpressionRejectsMalformedContext(t *testing.T) {
+	d := &Datasource{}
+	_, err := d.evaluateExpression(context.Background(), rules.Rule{Name: "Bad", Expression: "'Tag1'", Context: "no-semicolon-here"})
+	if err == nil {
+		t.Fatal("expected an error for a context with no \";\" separator, got nil")
+	}
+	if !strings.Contains(err.Error(), "malformed context") {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+}
+
+func TestQueryRecordingRuleRequiresReservedBasePath(t *testing.T) {
+	m := rules.NewManager()
+	if err := m.LoadYAML([]byte("groups:\n- name: g\n  rules:\n  - record: cached_value\n    expr: \"'Tag1'\"\n    context: base;Tag1\n")); err != nil {
+		t.Fatalf("LoadYAML error: %v", err)
+	}
+	m.Evaluate(time.Now(), func(r rules.Rule) (float64, error) { return 42, nil })
+
+	d := &Datasource{rulesManager: m}
+
+	query := backend.DataQuery{RefID: "A", JSON: json.RawMessage(`{"Target":"cached_value;cached_value"}`)}
+	handled, _ := d.queryRecordingRule(query)
+	if handled {
+		t.Fatal("expected an unqualified target matching a recording rule's name not to be rerouted to the cache")
+	}
+
+	query = backend.DataQuery{RefID: "A", JSON: json.RawMessage(`{"Target":"` + recordingRuleBasePath + `;cached_value"}`)}
+	handled, resp := d.queryRecordingRule(query)
+	if !handled {
+		t.Fatalf("expected a target addressed to %q to be served from the recording rule cache", recordingRuleBasePath)
+	}
+	if len(resp.Frames) != 1 {
+		t.Fatalf("expected a single frame, got %d", len(resp.Frames))
+	}
+}
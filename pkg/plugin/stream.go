@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// SubscribeStream is called by Grafana when a panel first subscribes to a
+// channel created by processBatchtoFrames (see frame.Meta.Channel). The
+// channel UUID must still be a live entry in channelConstruct.
+func (d *Datasource) SubscribeStream(ctx context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
+	d.channelConstructMutex.Lock()
+	_, ok := d.channelConstruct[req.Path]
+	d.channelConstructMutex.Unlock()
+	if !ok {
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusNotFound}, nil
+	}
+	return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusOK}, nil
+}
+
+// PublishStream is unused: streamed frames only flow from PI Web API to
+// Grafana, never the other way.
+func (d *Datasource) PublishStream(ctx context.Context, req *backend.PublishStreamRequest) (*backend.PublishStreamResponse, error) {
+	return &backend.PublishStreamResponse{Status: backend.PublishStreamStatusPermissionDenied}, nil
+}
+
+// RunStream fans PI Web API values for a single channel to its subscriber on
+// IntervalNanoSeconds, for as long as the stream's sender keeps up. Each
+// sender carries its own idle deadline (see stream_deadline.go): if polling
+// or sending stalls past that deadline, the sender is considered wedged and
+// dropped, its websocket connection (if it was the last sender for that
+// WebID) is closed, and the channelConstruct entry is reaped.
+func (d *Datasource) RunStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender) error {
+	d.channelConstructMutex.Lock()
+	channel, ok := d.channelConstruct[req.Path]
+	d.channelConstructMutex.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown stream channel %q", req.Path)
+	}
+	defer func() {
+		d.channelConstructMutex.Lock()
+		delete(d.channelConstruct, req.Path)
+		d.channelConstructMutex.Unlock()
+	}()
+
+	deadline := d.registerStreamSender(channel.WebID, sender)
+	defer d.dropStreamSender(channel.WebID, sender)
+
+	interval := time.Duration(channel.IntervalNanoSeconds)
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline.Done():
+			log.DefaultLogger.Warn("RunStream: sender exceeded its idle deadline, dropping", "webID", channel.WebID)
+			return nil
+		case <-ticker.C:
+			frame, err := d.buildStreamFrame(ctx, channel.WebID)
+			if err != nil {
+				log.DefaultLogger.Error("RunStream: error building frame", "webID", channel.WebID, "error", err)
+				continue
+			}
+			if err := sender.SendFrame(frame, data.IncludeAll); err != nil {
+				log.DefaultLogger.Error("RunStream: error sending frame, dropping sender", "webID", channel.WebID, "error", err)
+				return nil
+			}
+			deadline.SetDeadline(time.Now().Add(d.idleTimeout))
+		}
+	}
+}
+
+// buildStreamFrame resolves the current value for webID and shapes it into
+// the same kind of data.Frame processBatchtoFrames produces for a
+// non-streaming query.
+func (d *Datasource) buildStreamFrame(ctx context.Context, webID string) (*data.Frame, error) {
+	body, err := d.apiGet(ctx, d.settings.URL+"/streams/"+webID+"/value")
+	if err != nil {
+		return nil, err
+	}
+
+	var item PiBatchContentItem
+	if err := json.Unmarshal(body, &item); err != nil {
+		return nil, err
+	}
+
+	Type := d.getTypeForWebID(webID)
+	DigitalState := d.getDigitalStateforWebID(webID)
+	return convertItemsToDataFrame(webID, []PiBatchContentItem{item}, Type, DigitalState, false)
+}
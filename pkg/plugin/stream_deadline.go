@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// defaultIdleTimeout is used when a datasource instance's JSON settings do
+// not specify an idleTimeout override.
+const defaultIdleTimeout = 5 * time.Minute
+
+// streamSenderDeadline tracks the read/write deadlines for a single entry in
+// Datasource.sendersByWebID, modeled on the deadlineTimer pattern used by
+// Go's net package: a cancelCh is closed by time.AfterFunc once a deadline
+// elapses, so RunStream's fan-out loop can select on it and drop a sender
+// whose PI Web API channel has silently wedged instead of blocking forever.
+type streamSenderDeadline struct {
+	mu            sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+	cancelCh      chan struct{}
+	timer         *time.Timer
+}
+
+// newStreamSenderDeadline creates a deadline armed idleTimeout from now.
+func newStreamSenderDeadline(idleTimeout time.Duration) *streamSenderDeadline {
+	d := &streamSenderDeadline{cancelCh: make(chan struct{})}
+	d.arm(time.Now().Add(idleTimeout))
+	return d
+}
+
+// Done returns the channel that RunStream's select loop should watch; it is
+// closed once the current deadline elapses.
+func (d *streamSenderDeadline) Done() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancelCh
+}
+
+// SetDeadline arms both the read and write deadlines to t.
+func (d *streamSenderDeadline) SetDeadline(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.readDeadline = t
+	d.writeDeadline = t
+	d.arm(t)
+}
+
+// SetReadDeadline arms the read deadline to t.
+func (d *streamSenderDeadline) SetReadDeadline(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.readDeadline = t
+	d.arm(t)
+}
+
+// SetWriteDeadline arms the write deadline to t.
+func (d *streamSenderDeadline) SetWriteDeadline(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.writeDeadline = t
+	d.arm(t)
+}
+
+// arm must be called with d.mu held. It stops any in-flight timer and
+// schedules cancelCh to be replaced and closed at t.
+func (d *streamSenderDeadline) arm(t time.Time) {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	// Replace the channel so a sender that already observed the previous
+	// deadline elapse cannot be revived by a later SetDeadline call.
+	d.cancelCh = make(chan struct{})
+	cancelCh := d.cancelCh
+	d.timer = time.AfterFunc(time.Until(t), func() {
+		close(cancelCh)
+	})
+}
+
+// registerStreamSender adds sender to sendersByWebID[webID] with a fresh
+// deadline armed at d.idleTimeout, and returns it so RunStream can select on
+// its Done() channel alongside backend.StreamSender.SendFrame.
+func (d *Datasource) registerStreamSender(webID string, sender *backend.StreamSender) *streamSenderDeadline {
+	d.sendersByWebIDMutex.Lock()
+	defer d.sendersByWebIDMutex.Unlock()
+
+	if d.sendersByWebID[webID] == nil {
+		d.sendersByWebID[webID] = make(map[*backend.StreamSender]*streamSenderDeadline)
+	}
+	deadline := newStreamSenderDeadline(d.idleTimeout)
+	d.sendersByWebID[webID][sender] = deadline
+	return deadline
+}
+
+// dropStreamSender removes sender from sendersByWebID[webID]. If no senders
+// remain for that WebID, the underlying websocket connection is closed and
+// reaped along with its sendersByWebID entry, since nothing is left to
+// deliver its frames to.
+func (d *Datasource) dropStreamSender(webID string, sender *backend.StreamSender) {
+	d.sendersByWebIDMutex.Lock()
+	defer d.sendersByWebIDMutex.Unlock()
+
+	senders := d.sendersByWebID[webID]
+	if senders == nil {
+		return
+	}
+	delete(senders, sender)
+	if len(senders) > 0 {
+		return
+	}
+	delete(d.sendersByWebID, webID)
+
+	d.websocketConnectionsMutex.Lock()
+	defer d.websocketConnectionsMutex.Unlock()
+	if conn, ok := d.websocketConnections[webID]; ok {
+		if err := conn.Close(); err != nil {
+			log.DefaultLogger.Error("dropStreamSender: error closing websocket", "webID", webID, "error", err)
+		}
+		delete(d.websocketConnections, webID)
+	}
+}
@@ -0,0 +1,104 @@
+package rules
+
+import (
+	"fmt"
+	"time"
+)
+
+// RuleGroupsResponse is the `data` payload of a Prometheus-compatible
+// GET /rules response.
+type RuleGroupsResponse struct {
+	Groups []RuleGroupJSON `json:"groups"`
+}
+
+// RuleGroupJSON is a single rule group in a GET /rules response.
+type RuleGroupJSON struct {
+	Name  string     `json:"name"`
+	Rules []RuleJSON `json:"rules"`
+}
+
+// RuleJSON is a single alerting or recording rule in a GET /rules response.
+type RuleJSON struct {
+	Name        string            `json:"name"`
+	Query       string            `json:"query"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	State       State             `json:"state,omitempty"`
+	Health      string            `json:"health"`
+	Type        string            `json:"type"`
+}
+
+// AlertsResponse is the `data` payload of a Prometheus-compatible
+// GET /alerts response.
+type AlertsResponse struct {
+	Alerts []AlertJSON `json:"alerts"`
+}
+
+// AlertJSON is a single active alert in a GET /alerts response.
+type AlertJSON struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	State       State             `json:"state"`
+	ActiveAt    *time.Time        `json:"activeAt,omitempty"`
+	Value       string            `json:"value"`
+}
+
+// RulesJSON renders the loaded groups and their current state into the
+// Prometheus-compatible shape served by GET /rules.
+func (m *Manager) RulesJSON() RuleGroupsResponse {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	response := RuleGroupsResponse{Groups: make([]RuleGroupJSON, 0, len(m.groups))}
+	for _, group := range m.groups {
+		groupJSON := RuleGroupJSON{Name: group.Name, Rules: make([]RuleJSON, 0, len(group.Rules))}
+		for _, rule := range group.Rules {
+			ruleJSON := RuleJSON{
+				Name:        rule.ID(),
+				Query:       rule.Expression,
+				Labels:      rule.Labels,
+				Annotations: rule.Annotations,
+				Health:      "ok",
+			}
+			if rule.IsRecording() {
+				ruleJSON.Type = "recording"
+			} else {
+				ruleJSON.Type = "alerting"
+				if state, ok := m.alertStates[rule.ID()]; ok {
+					ruleJSON.State = state.State
+				} else {
+					ruleJSON.State = StateInactive
+				}
+			}
+			groupJSON.Rules = append(groupJSON.Rules, ruleJSON)
+		}
+		response.Groups = append(response.Groups, groupJSON)
+	}
+	return response
+}
+
+// AlertsJSON renders every currently pending or firing alert into the
+// Prometheus-compatible shape served by GET /alerts.
+func (m *Manager) AlertsJSON() AlertsResponse {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	response := AlertsResponse{}
+	for _, state := range m.alertStates {
+		if state.State == StateInactive {
+			continue
+		}
+		activeAt := state.ActiveAt
+		alert := AlertJSON{
+			Labels:      state.Rule.Labels,
+			Annotations: state.Rule.Annotations,
+			State:       state.State,
+			Value:       fmt.Sprintf("%v", state.Value),
+		}
+		if !activeAt.IsZero() {
+			alert.ActiveAt = &activeAt
+		}
+		response.Alerts = append(response.Alerts, alert)
+	}
+	return response
+}
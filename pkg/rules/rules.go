@@ -0,0 +1,195 @@
+// Package rules implements a Prometheus-compatible alerting and recording
+// rule evaluation subsystem over PI calculation expressions. Rule groups are
+// defined in YAML (the same `for:`/`labels:`/`annotations:` shape Prometheus
+// and Thanos use) and evaluated on a schedule by the caller; Manager only
+// owns the rule definitions and the resulting in-memory state.
+package rules
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// State is the Prometheus-compatible lifecycle of an alerting rule.
+type State string
+
+const (
+	StateInactive State = "inactive"
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+)
+
+// Rule is a single PI calculation expression evaluated on a schedule, in
+// either alerting mode (Name set) or recording mode (Record set).
+type Rule struct {
+	Name       string `yaml:"alert,omitempty"`
+	Record     string `yaml:"record,omitempty"`
+	Expression string `yaml:"expr"`
+	// Context is the AF element path ("<base path>;<target>", the same shape
+	// as PIWebAPIQuery.Target) used to anchor Expression's relative
+	// references when it is evaluated. Raw PI point paths are not resolved.
+	Context     string            `yaml:"context"`
+	For         time.Duration     `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// IsRecording reports whether the rule caches a scalar value for reuse
+// instead of tracking alert state.
+func (r Rule) IsRecording() bool {
+	return r.Record != ""
+}
+
+// ID is the rule's alert name or recording name, whichever applies.
+func (r Rule) ID() string {
+	if r.IsRecording() {
+		return r.Record
+	}
+	return r.Name
+}
+
+// Group is a named collection of rules. Interval is parsed from YAML but
+// Evaluate does not read it: every group is evaluated each time the caller
+// runs Evaluate.
+type Group struct {
+	Name     string        `yaml:"name"`
+	Interval time.Duration `yaml:"interval,omitempty"`
+	Rules    []Rule        `yaml:"rules"`
+}
+
+// File is the top-level shape of a rules YAML document.
+type File struct {
+	Groups []Group `yaml:"groups"`
+}
+
+// AlertState is the in-memory lifecycle state tracked for one alerting rule.
+type AlertState struct {
+	Rule      Rule
+	State     State
+	ActiveAt  time.Time
+	Value     float64
+	pendingAt time.Time
+}
+
+// Manager owns the loaded rule groups, the in-memory alert state machine for
+// alerting rules, and the cached scalar value of every recording rule.
+type Manager struct {
+	mu             sync.RWMutex
+	groups         []Group
+	alertStates    map[string]*AlertState
+	recordingCache map[string]float64
+}
+
+// NewManager returns an empty Manager ready to have rule groups loaded into
+// it via LoadYAML.
+func NewManager() *Manager {
+	return &Manager{
+		alertStates:    make(map[string]*AlertState),
+		recordingCache: make(map[string]float64),
+	}
+}
+
+// LoadYAML parses a rules YAML document and replaces the currently loaded
+// groups with the ones found in it. Existing alert/recording state for rules
+// that are still present is left untouched.
+func (m *Manager) LoadYAML(doc []byte) error {
+	var f File
+	if err := yaml.Unmarshal(doc, &f); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.groups = f.Groups
+	return nil
+}
+
+// Groups returns a snapshot of the loaded rule groups.
+func (m *Manager) Groups() []Group {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	groups := make([]Group, len(m.groups))
+	copy(groups, m.groups)
+	return groups
+}
+
+// Evaluate runs eval against every loaded rule's expression, caches recording
+// rule values, and drives alerting rules through their pending/firing state
+// machine. eval is expected to resolve a PI calculation expression to its
+// current scalar value.
+//
+// eval (a PI Web API round-trip) is deliberately called outside of m.mu so a
+// slow or hung request does not block RulesJSON/AlertsJSON/RecordingValue.
+// Rules are evaluated one after another, so such a request still delays the
+// state updates of every rule evaluated after it.
+func (m *Manager) Evaluate(now time.Time, eval func(rule Rule) (float64, error)) {
+	for _, group := range m.Groups() {
+		for _, rule := range group.Rules {
+			value, err := eval(rule)
+			if err != nil {
+				continue
+			}
+
+			m.mu.Lock()
+			if rule.IsRecording() {
+				m.recordingCache[rule.Record] = value
+			} else {
+				m.evaluateAlertLocked(rule, value, now)
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+func (m *Manager) evaluateAlertLocked(rule Rule, value float64, now time.Time) {
+	state, ok := m.alertStates[rule.ID()]
+	if !ok {
+		state = &AlertState{Rule: rule, State: StateInactive}
+		m.alertStates[rule.ID()] = state
+	}
+	state.Value = value
+
+	if math.IsNaN(value) || value == 0 {
+		state.State = StateInactive
+		state.ActiveAt = time.Time{}
+		state.pendingAt = time.Time{}
+		return
+	}
+
+	switch state.State {
+	case StateInactive:
+		state.State = StatePending
+		state.pendingAt = now
+		fallthrough
+	case StatePending:
+		if now.Sub(state.pendingAt) >= rule.For {
+			state.State = StateFiring
+			state.ActiveAt = state.pendingAt
+		}
+	case StateFiring:
+		// Already firing; nothing to transition.
+	}
+}
+
+// RecordingValue returns the cached scalar result of a recording rule by
+// name, letting QueryData serve a matching target without a round-trip to
+// PI Web API.
+func (m *Manager) RecordingValue(name string) (float64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.recordingCache[name]
+	return v, ok
+}
+
+// AlertStates returns a snapshot of every tracked alerting rule's state.
+func (m *Manager) AlertStates() []*AlertState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	states := make([]*AlertState, 0, len(m.alertStates))
+	for _, s := range m.alertStates {
+		states = append(states, s)
+	}
+	return states
+}
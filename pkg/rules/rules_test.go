@@ -0,0 +1,89 @@
+package rules
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func managerWithGroup(g Group) *Manager {
+	m := NewManager()
+	m.groups = []Group{g}
+	return m
+}
+
+func TestEvaluateAlertPendingThenFiring(t *testing.T) {
+	rule := Rule{Name: "HighValue", Expression: "'Tag1'", Context: "base;Tag1", For: time.Minute}
+	m := managerWithGroup(Group{Name: "g", Rules: []Rule{rule}})
+
+	now := time.Now()
+	m.Evaluate(now, func(r Rule) (float64, error) { return 1, nil })
+
+	states := m.AlertStates()
+	if len(states) != 1 || states[0].State != StatePending {
+		t.Fatalf("expected pending after first nonzero evaluation, got %+v", states)
+	}
+
+	m.Evaluate(now.Add(2*time.Minute), func(r Rule) (float64, error) { return 1, nil })
+	states = m.AlertStates()
+	if states[0].State != StateFiring {
+		t.Fatalf("expected firing once For has elapsed, got %v", states[0].State)
+	}
+}
+
+func TestEvaluateAlertForZeroFiresImmediately(t *testing.T) {
+	rule := Rule{Name: "Immediate", Expression: "'Tag1'", Context: "base;Tag1", For: 0}
+	m := managerWithGroup(Group{Name: "g", Rules: []Rule{rule}})
+
+	m.Evaluate(time.Now(), func(r Rule) (float64, error) { return 1, nil })
+
+	states := m.AlertStates()
+	if len(states) != 1 || states[0].State != StateFiring {
+		t.Fatalf("expected immediate firing for a For:0 rule, got %+v", states)
+	}
+}
+
+func TestEvaluateAlertZeroAndNaNAreInactive(t *testing.T) {
+	rule := Rule{Name: "Flaky", Expression: "'Tag1'", Context: "base;Tag1", For: 0}
+	m := managerWithGroup(Group{Name: "g", Rules: []Rule{rule}})
+
+	m.Evaluate(time.Now(), func(r Rule) (float64, error) { return 1, nil })
+	if states := m.AlertStates(); states[0].State != StateFiring {
+		t.Fatalf("expected firing before the zero evaluation, got %v", states[0].State)
+	}
+
+	m.Evaluate(time.Now(), func(r Rule) (float64, error) { return 0, nil })
+	if states := m.AlertStates(); states[0].State != StateInactive {
+		t.Fatalf("expected zero value to reset to inactive, got %v", states[0].State)
+	}
+
+	m.Evaluate(time.Now(), func(r Rule) (float64, error) { return 1, nil })
+	m.Evaluate(time.Now(), func(r Rule) (float64, error) { return math.NaN(), nil })
+	if states := m.AlertStates(); states[0].State != StateInactive {
+		t.Fatalf("expected NaN value to reset to inactive, got %v", states[0].State)
+	}
+}
+
+func TestEvaluateRecordingRuleCachesValue(t *testing.T) {
+	rule := Rule{Record: "cached_value", Expression: "'Tag1'", Context: "base;Tag1"}
+	m := managerWithGroup(Group{Name: "g", Rules: []Rule{rule}})
+
+	m.Evaluate(time.Now(), func(r Rule) (float64, error) { return 42, nil })
+
+	value, ok := m.RecordingValue("cached_value")
+	if !ok || value != 42 {
+		t.Fatalf("expected cached recording value 42, got %v (ok=%v)", value, ok)
+	}
+}
+
+func TestEvaluateSkipsRuleOnEvalError(t *testing.T) {
+	rule := Rule{Record: "cached_value", Expression: "'Tag1'", Context: "base;Tag1"}
+	m := managerWithGroup(Group{Name: "g", Rules: []Rule{rule}})
+
+	m.Evaluate(time.Now(), func(r Rule) (float64, error) { return 0, errors.New("pi web api unreachable") })
+
+	if _, ok := m.RecordingValue("cached_value"); ok {
+		t.Fatalf("expected no cached value after a failed evaluation")
+	}
+}